@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ColumnSpec describes a single column of a workload's generated table.
+type ColumnSpec struct {
+	Name       string `yaml:"name"`
+	Type       string `yaml:"type"`
+	PrimaryKey bool   `yaml:"primary_key"`
+}
+
+// SkewSpec describes how primary-key values should be distributed when a
+// workload's rows are generated.
+type SkewSpec struct {
+	Profile         string  `yaml:"profile"` // uniform|hotspot|zipfian|sequential-gap
+	ZipfS           float64 `yaml:"zipf_s"`
+	HotspotFraction float64 `yaml:"hotspot_fraction"`
+	Gap             int64   `yaml:"gap"`
+}
+
+// WorkloadSpec is a single entry of the benchmark suite: a table schema, a
+// row count, a region-split strategy, a skew profile, and the dumpling flags
+// to run against the resulting table.
+type WorkloadSpec struct {
+	Name         string       `yaml:"name"`
+	Columns      []ColumnSpec `yaml:"columns"`
+	Rows         int          `yaml:"rows"`
+	Regions      int          `yaml:"regions"`
+	Skew         SkewSpec     `yaml:"skew"`
+	DumplingArgs []string     `yaml:"dumpling_args"`
+}
+
+// BenchConfig is the top-level shape of the `--config` YAML file.
+type BenchConfig struct {
+	Workloads []WorkloadSpec `yaml:"workloads"`
+}
+
+func loadBenchConfig(path string) (*BenchConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read config %s", path)
+	}
+	var cfg BenchConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "parse config %s", path)
+	}
+	if len(cfg.Workloads) == 0 {
+		return nil, errors.Errorf("config %s defines no workloads", path)
+	}
+	for i := range cfg.Workloads {
+		w := &cfg.Workloads[i]
+		if w.Name == "" {
+			return nil, errors.Errorf("workload %d has no name", i)
+		}
+		if len(w.Columns) == 0 {
+			return nil, errors.Errorf("workload %s defines no columns", w.Name)
+		}
+		if w.Skew.Profile == "" {
+			w.Skew.Profile = "uniform"
+		}
+	}
+	return &cfg, nil
+}
+
+func tableNameForWorkload(w WorkloadSpec) string {
+	return "wl_" + w.Name
+}
+
+func createTableSQLForWorkload(w WorkloadSpec) string {
+	var cols []string
+	var pk []string
+	for _, c := range w.Columns {
+		def := fmt.Sprintf("%s %s", c.Name, c.Type)
+		cols = append(cols, def)
+		if c.PrimaryKey {
+			pk = append(pk, c.Name)
+		}
+	}
+	if len(pk) > 0 {
+		cols = append(cols, fmt.Sprintf("primary key (%s)", strings.Join(pk, ", ")))
+	}
+	return fmt.Sprintf("create table %s (%s);", tableNameForWorkload(w), strings.Join(cols, ", "))
+}
+
+// prepareWorkload creates the table for a single workload and fills it with
+// generated rows according to its skew profile, using conn for DDL and pool
+// for the (possibly parallel) row-loading phase.
+func prepareWorkload(ctx context.Context, pool *sql.DB, conn *sql.Conn, w WorkloadSpec) error {
+	table := tableNameForWorkload(w)
+	if err := runSQL(ctx, conn, fmt.Sprintf("drop table if exists %s;", table)); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, conn, createTableSQLForWorkload(w)); err != nil {
+		return err
+	}
+	if w.Regions != 0 {
+		query := fmt.Sprintf("split table %s between (0) and (%d) regions %d", table, w.Rows, w.Regions)
+		if err := runSQL(ctx, conn, query); err != nil {
+			return err
+		}
+	}
+	if loadMethod == loadMethodInfile {
+		if err := loadRowsBulk(ctx, pool, table, w.Columns, w.Rows, w.Skew, generatorParallelism); err != nil {
+			return err
+		}
+	} else {
+		dist, err := newDistribution(w.Skew, w.Rows, 0)
+		if err != nil {
+			return err
+		}
+		if err := loadRows(ctx, conn, table, w.Columns, w.Rows, dist); err != nil {
+			return err
+		}
+	}
+	return reportRegionCounts(ctx, conn, table)
+}
+
+// prepareWorkloads runs prepareWorkload for every workload in the config,
+// bounded by concurrency goroutines.
+func prepareWorkloads(ctx context.Context, pool *sql.DB, cfg *BenchConfig, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	errCh := make(chan error, len(cfg.Workloads))
+	var wg sync.WaitGroup
+	for _, w := range cfg.Workloads {
+		w := w
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			conn, err := pool.Conn(ctx)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			defer conn.Close()
+			if err := runSQL(ctx, conn, "use test"); err != nil {
+				errCh <- err
+				return
+			}
+			if err := prepareWorkload(ctx, pool, conn, w); err != nil {
+				errCh <- errors.Wrapf(err, "workload %s", w.Name)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workloadResult is one row of the summary table printed after a suite run.
+type workloadResult struct {
+	Name        string
+	Rows        int
+	Elapsed     time.Duration
+	Files       int
+	OutputBytes int64
+}
+
+func (r workloadResult) mbPerSecond() float64 {
+	secs := r.Elapsed.Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(r.OutputBytes) / (1024 * 1024) / secs
+}
+
+// runWorkloads dumps each workload's table via dumpData and collects a
+// summary row per workload.
+func runWorkloads(ctx context.Context, cfg *BenchConfig) ([]workloadResult, error) {
+	var results []workloadResult
+	for _, w := range cfg.Workloads {
+		table := tableNameForWorkload(w)
+		outDir := filepath.Join("bench_out", w.Name)
+		args := []string{
+			"--host", connTLS.host,
+			"--port", fmt.Sprintf("%d", connTLS.port),
+			"--user", connTLS.user,
+			"--password", connTLS.password,
+			"--filter", "test." + table,
+			"--output", outDir,
+		}
+		args = append(args, tlsDumplingArgs(connTLS)...)
+		args = append(args, w.DumplingArgs...)
+		start := time.Now()
+		// Workloads have no equivalent of the single-table --chk_rows flag
+		// (chunking is whatever --rows the workload's own dumpling_args set),
+		// so ChunkRows is left at zero rather than reporting the unrelated
+		// top-level flag default.
+		if err := dumpDataWithMetrics(ctx, dumplingBin, args, outDir, metricsOut, dumplingPprofAddr, dumpRunInfo{
+			Rows:         w.Rows,
+			Regions:      w.Regions,
+			Distribution: w.Skew.Profile,
+		}); err != nil {
+			return nil, errors.Wrapf(err, "workload %s", w.Name)
+		}
+		elapsed := time.Since(start)
+		files, size, err := dirStats(outDir)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, workloadResult{
+			Name:        w.Name,
+			Rows:        w.Rows,
+			Elapsed:     elapsed,
+			Files:       files,
+			OutputBytes: size,
+		})
+	}
+	return results, nil
+}
+
+func dirStats(dir string) (files int, size int64, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		files++
+		size += e.Size()
+	}
+	return files, size, nil
+}
+
+func printWorkloadSummary(results []workloadResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKLOAD\tROWS\tWALL TIME\tMB/S\tFILES")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.2f\t%d\n", r.Name, r.Rows, r.Elapsed.Round(time.Millisecond), r.mbPerSecond(), r.Files)
+	}
+	w.Flush()
+}
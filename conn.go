@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+const tlsConfigName = "dumpling-bench-tls"
+
+// tlsSettings holds the flags needed to connect over TLS, shared by the
+// prepare-phase DSN and the benchmarked dumpling invocation so both sides of
+// the run talk to the server the same way.
+type tlsSettings struct {
+	host     string
+	port     int
+	user     string
+	password string
+	ca       string
+	cert     string
+	key      string
+}
+
+// registerTLSConfig registers a *tls.Config under tlsConfigName when CA/cert
+// paths are given, so getDSN can reference it via tls=dumpling-bench-tls. It
+// is a no-op (no error) when no TLS material is configured.
+func registerTLSConfig(s tlsSettings) error {
+	if s.ca == "" && s.cert == "" {
+		return nil
+	}
+	cfg := &tls.Config{}
+	if s.ca != "" {
+		pem, err := ioutil.ReadFile(s.ca)
+		if err != nil {
+			return errors.Wrapf(err, "read ca %s", s.ca)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.Errorf("failed to parse ca cert %s", s.ca)
+		}
+		cfg.RootCAs = pool
+	}
+	if s.cert != "" && s.key != "" {
+		cert, err := tls.LoadX509KeyPair(s.cert, s.key)
+		if err != nil {
+			return errors.Wrapf(err, "load cert/key %s/%s", s.cert, s.key)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return mysql.RegisterTLSConfig(tlsConfigName, cfg)
+}
+
+// bracketHost wraps an IPv6 literal in brackets for the go-sql-driver
+// tcp(host:port) DSN form; hostnames and IPv4 literals pass through
+// unchanged.
+func bracketHost(host string) string {
+	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+func getDSNWithTLS(s tlsSettings) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&readTimeout=%s&writeTimeout=30s&interpolateParams=true&maxAllowedPacket=0",
+		s.user, s.password, bracketHost(s.host), s.port, "test", "900s")
+	if s.ca != "" || s.cert != "" {
+		dsn += "&tls=" + tlsConfigName
+	}
+	return dsn
+}
+
+// getPostgresDSN builds a lib/pq keyword/value DSN for the --source=postgres
+// path, reusing the same host/port/user/password/ca flags as the MySQL DSN.
+func getPostgresDSN(s tlsSettings) string {
+	sslMode := "disable"
+	if s.ca != "" {
+		sslMode = "verify-ca"
+	}
+	parts := []string{
+		fmt.Sprintf("host=%s", s.host),
+		fmt.Sprintf("port=%d", s.port),
+		fmt.Sprintf("user=%s", s.user),
+		fmt.Sprintf("password=%s", s.password),
+		"dbname=test",
+		fmt.Sprintf("sslmode=%s", sslMode),
+	}
+	if s.ca != "" {
+		parts = append(parts, fmt.Sprintf("sslrootcert=%s", s.ca))
+	}
+	if s.cert != "" {
+		parts = append(parts, fmt.Sprintf("sslcert=%s", s.cert))
+	}
+	if s.key != "" {
+		parts = append(parts, fmt.Sprintf("sslkey=%s", s.key))
+	}
+	return strings.Join(parts, " ")
+}
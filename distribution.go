@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyDistribution yields the next primary-key value to insert, allowing
+// prepareWorkload to materialize uniform, hotspot or zipfian key density.
+type keyDistribution interface {
+	next(i int) int64
+}
+
+type uniformDistribution struct{}
+
+func (uniformDistribution) next(i int) int64 {
+	return int64(i + 1)
+}
+
+// sequentialGapDistribution keeps keys monotonic but leaves a fixed-size
+// gap between consecutive values, spreading the same row count over a much
+// wider key range than uniformDistribution.
+type sequentialGapDistribution struct {
+	gap int64
+}
+
+func (d sequentialGapDistribution) next(i int) int64 {
+	return int64(i)*d.gap + 1
+}
+
+// hotspotDistribution places a fraction of the rows into a small key window
+// at the end of the range, and spreads the rest uniformly before it. Keys
+// stay unique and contiguous (row i gets key i+1 inside and outside the
+// window), so the window's key density is the same as the rest of the range.
+type hotspotDistribution struct {
+	rows     int
+	fraction float64
+}
+
+func (d hotspotDistribution) next(i int) int64 {
+	hotRows := int(float64(d.rows) * d.fraction)
+	if hotRows <= 0 {
+		return int64(i + 1)
+	}
+	hotStart := d.rows - hotRows
+	if i >= hotStart {
+		// The hot window must hold at least hotRows distinct values to
+		// avoid primary-key collisions, so offset directly from hotStart
+		// instead of folding the range through a narrower modulo.
+		return int64(hotStart + 1 + (i - hotStart))
+	}
+	return int64(i + 1)
+}
+
+// zipfianDistribution biases primary-key values toward the low end of the
+// key range, using math/rand's Zipf generator (s > 1 is its skew parameter;
+// higher values concentrate more rows onto the smallest keys).
+type zipfianDistribution struct {
+	rows int
+	z    *rand.Zipf
+}
+
+func (d zipfianDistribution) next(_ int) int64 {
+	return int64(d.z.Uint64()) + 1
+}
+
+// newDistribution builds the keyDistribution for spec, seeding any
+// distribution that draws from math/rand (currently zipfian) from seed so
+// independent callers - such as loadRowsBulk's per-worker goroutines - can
+// pass a distinct seed per key range and avoid emitting identical sequences.
+func newDistribution(spec SkewSpec, rows int, seed int64) (keyDistribution, error) {
+	switch spec.Profile {
+	case "", "uniform":
+		return uniformDistribution{}, nil
+	case "hotspot":
+		frac := spec.HotspotFraction
+		if frac <= 0 {
+			frac = 0.01
+		}
+		return hotspotDistribution{rows: rows, fraction: frac}, nil
+	case "zipfian":
+		s := spec.ZipfS
+		if s <= 1 {
+			s = 1.2
+		}
+		rnd := rand.New(rand.NewSource(seed + 1))
+		z := rand.NewZipf(rnd, s, 1, uint64(rows-1))
+		return zipfianDistribution{rows: rows, z: z}, nil
+	case "sequential-gap":
+		gap := spec.Gap
+		if gap <= 0 {
+			gap = 1000
+		}
+		return sequentialGapDistribution{gap: gap}, nil
+	default:
+		return nil, errors.Errorf("unknown skew profile: %s", spec.Profile)
+	}
+}
+
+// loadRows inserts rowCount generated rows into table using the current
+// batched INSERT strategy, with primary-key values drawn from dist.
+func loadRows(ctx context.Context, conn *sql.Conn, table string, columns []ColumnSpec, rowCount int, dist keyDistribution) error {
+	var sb strings.Builder
+	for i := 0; i < rowCount; i++ {
+		if sb.Len() > 1000000 {
+			if err := runSQL(ctx, conn, fmt.Sprintf("insert into %s values %s;", table, sb.String())); err != nil {
+				return err
+			}
+			sb.Reset()
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(rowValues(columns, dist.next(i), i))
+	}
+	if sb.Len() > 0 {
+		if err := runSQL(ctx, conn, fmt.Sprintf("insert into %s values %s;", table, sb.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowValues renders one row's literal tuple, using key for the primary-key
+// column and i to derive deterministic filler values for the rest.
+func rowValues(columns []ColumnSpec, key int64, i int) string {
+	var vals []string
+	for _, c := range columns {
+		if c.PrimaryKey {
+			vals = append(vals, fmt.Sprintf("%d", key))
+			continue
+		}
+		if strings.Contains(strings.ToLower(c.Type), "varchar") || strings.Contains(strings.ToLower(c.Type), "text") {
+			vals = append(vals, fmt.Sprintf("'string_payload_payload_payload'"))
+		} else {
+			vals = append(vals, fmt.Sprintf("%d", i+1))
+		}
+	}
+	return "(" + strings.Join(vals, ", ") + ")"
+}
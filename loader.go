@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+const (
+	loadMethodInsert = "insert"
+	loadMethodInfile = "load-infile"
+)
+
+// rowReader streams the CSV-encoded rows for the half-open index range
+// [start, end) as an io.Reader, suitable for registration with
+// mysql.RegisterReaderHandler. Rows are generated lazily so 100M-row
+// preparations don't need to buffer the whole table in memory.
+type rowReader struct {
+	columns []ColumnSpec
+	dist    keyDistribution
+	start   int
+	end     int
+	cur     int
+	buf     []byte
+}
+
+func newRowReader(columns []ColumnSpec, dist keyDistribution, start, end int) *rowReader {
+	return &rowReader{columns: columns, dist: dist, start: start, end: end, cur: start}
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.cur >= r.end {
+			return 0, io.EOF
+		}
+		line := csvRow(r.columns, r.dist.next(r.cur), r.cur)
+		r.buf = []byte(line + "\n")
+		r.cur++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// csvRow renders one row in CSV form matching rowValues' literal ordering,
+// so the LOAD DATA path and the INSERT path produce identical data.
+func csvRow(columns []ColumnSpec, key int64, i int) string {
+	var fields []string
+	for _, c := range columns {
+		if c.PrimaryKey {
+			fields = append(fields, strconv.FormatInt(key, 10))
+			continue
+		}
+		if strings.Contains(strings.ToLower(c.Type), "varchar") || strings.Contains(strings.ToLower(c.Type), "text") {
+			fields = append(fields, "string_payload_payload_payload")
+		} else {
+			fields = append(fields, strconv.Itoa(i+1))
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// loadRowsBulk loads rowCount rows into table via LOAD DATA LOCAL INFILE,
+// splitting the key range across parallelism goroutines that each open their
+// own connection and stream a disjoint slice of the range.
+func loadRowsBulk(ctx context.Context, pool *sql.DB, table string, columns []ColumnSpec, rowCount int, skew SkewSpec, parallelism int) error {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	colNames := make([]string, 0, len(columns))
+	for _, c := range columns {
+		colNames = append(colNames, c.Name)
+	}
+
+	var seq uint64
+	var wg sync.WaitGroup
+	errCh := make(chan error, parallelism)
+	chunk := (rowCount + parallelism - 1) / parallelism
+	for worker := 0; worker < parallelism; worker++ {
+		start := worker * chunk
+		end := start + chunk
+		if end > rowCount {
+			end = rowCount
+		}
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			dist, err := newDistribution(skew, rowCount, int64(start))
+			if err != nil {
+				errCh <- err
+				return
+			}
+			name := fmt.Sprintf("%s-%d", table, atomic.AddUint64(&seq, 1))
+			reader := bufio.NewReader(newRowReader(columns, dist, start, end))
+			mysql.RegisterReaderHandler(name, func() io.Reader { return reader })
+			defer mysql.DeregisterReaderHandler(name)
+
+			conn, err := pool.Conn(ctx)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			defer conn.Close()
+			if err := runSQL(ctx, conn, "use test"); err != nil {
+				errCh <- err
+				return
+			}
+			query := fmt.Sprintf("load data local infile 'Reader::%s' into table %s fields terminated by ',' lines terminated by '\\n' (%s);",
+				name, table, strings.Join(colNames, ","))
+			if err := runSQL(ctx, conn, query); err != nil {
+				errCh <- errors.Wrapf(err, "load rows [%d,%d) into %s", start, end, table)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,15 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
-	"strings"
-	"time"
+	"path/filepath"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
@@ -17,30 +14,81 @@ import (
 )
 
 const (
-	flagRowCount    = "rows"
-	flagChunkRows   = "chk_rows"
-	flagRegionCount = "regions"
-	flagSkewed      = "skewed"
-	flagDumplingBin = "dumpling"
-	flagAction      = "action"
+	flagRowCount             = "rows"
+	flagChunkRows            = "chk_rows"
+	flagRegionCount          = "regions"
+	flagDistribution         = "distribution"
+	flagZipfS                = "zipf-s"
+	flagHotspotFraction      = "hotspot-fraction"
+	flagDumplingBin          = "dumpling"
+	flagAction               = "action"
+	flagConfig               = "config"
+	flagPrepareConcurrency   = "prepare-concurrency"
+	flagHost                 = "host"
+	flagPort                 = "port"
+	flagUser                 = "user"
+	flagPassword             = "password"
+	flagCA                   = "ca"
+	flagCert                 = "cert"
+	flagKey                  = "key"
+	flagLoadMethod           = "load-method"
+	flagGeneratorParallelism = "generator-parallelism"
+	flagOutput               = "output"
+	flagMetricsOut           = "metrics-out"
+	flagPprofAddr            = "pprof-addr"
+	flagDumplingPprofAddr    = "dumpling-pprof-addr"
+	flagSource               = "source"
+	flagDumpTool             = "dump-tool"
 )
 
 var (
-	rowCount      int
-	chunkRowCount int
-	regionCount   int
-	skewed        bool
-	dumplingBin   string
-	action        string
+	rowCount             int
+	chunkRowCount        int
+	regionCount          int
+	distribution         string
+	zipfS                float64
+	hotspotFraction      float64
+	dumplingBin          string
+	action               string
+	configPath           string
+	prepareConcurrency   int
+	connTLS              tlsSettings
+	loadMethod           string
+	generatorParallelism int
+	outputDir            string
+	metricsOut           string
+	pprofAddr            string
+	dumplingPprofAddr    string
+	source               string
+	dumpTool             string
 )
 
 func parseFlags(flags *pflag.FlagSet) (err error) {
 	flags.Int(flagRowCount, 100000, "Number of rows to generate in a table, default 100000")
 	flags.Int(flagChunkRows, 10000, "Number of rows to split chunk, default 100000")
 	flags.Int(flagRegionCount, 16, "Number of regions of the table, default 16")
-	flags.Bool(flagSkewed, false, "Whether the data is heavily skewed, default false")
+	flags.String(flagDistribution, "uniform", "{uniform|zipfian|hotspot|sequential-gap}, the primary-key distribution of generated rows, default uniform")
+	flags.Float64(flagZipfS, 1.2, "The zipfian skew parameter s (s>1), used when --distribution=zipfian, default 1.2")
+	flags.Float64(flagHotspotFraction, 0.01, "Fraction of rows placed in the hot key window, used when --distribution=hotspot, default 0.01")
 	flags.String(flagDumplingBin, "./dumpling", "The binary of dumpling, default ./dumpling")
 	flags.String(flagAction, "all", "{prepare|run|all}, default all")
+	flags.String(flagConfig, "", "Path to a YAML file describing a suite of workloads to run, default none (single-table mode)")
+	flags.Int(flagPrepareConcurrency, 4, "Number of workloads to prepare in parallel when --config is set, default 4")
+	flags.String(flagHost, "127.0.0.1", "The host to connect to, default 127.0.0.1")
+	flags.Int(flagPort, 4000, "The port to connect to, default 4000")
+	flags.String(flagUser, "root", "The user to connect as, default root")
+	flags.String(flagPassword, "", "The password to connect with, default empty")
+	flags.String(flagCA, "", "Path to the CA cert used to verify the server's TLS certificate, default none (no TLS)")
+	flags.String(flagCert, "", "Path to the client TLS cert, default none")
+	flags.String(flagKey, "", "Path to the client TLS key, default none")
+	flags.String(flagLoadMethod, loadMethodInfile, "{insert|load-infile}, how prepareData loads generated rows, default load-infile")
+	flags.Int(flagGeneratorParallelism, 1, "Number of loader goroutines streaming disjoint key ranges when --load-method=load-infile, default 1")
+	flags.String(flagOutput, "dumpling_bench_output", "The dumpling output directory for the single-table path, default dumpling_bench_output")
+	flags.String(flagMetricsOut, "", "Path to append a JSON/CSV metrics record to after each dumpling run, default none")
+	flags.String(flagPprofAddr, "", "Address to serve the harness's own net/http/pprof endpoint on, default none (disabled)")
+	flags.String(flagDumplingPprofAddr, "", "Address of the dumpling process's pprof endpoint to snapshot around the run, default none (disabled)")
+	flags.String(flagSource, sourceTiDB, "{tidb|mysql|postgres}, the database to prepare data in and dump from, default tidb")
+	flags.String(flagDumpTool, "pg_dump", "The dump tool binary to benchmark when --source=postgres, default pg_dump")
 	flags.Bool("help", false, "Print help message and quit")
 	pflag.Parse()
 	if printHelp, err := pflag.CommandLine.GetBool("help"); printHelp || err != nil {
@@ -62,7 +110,15 @@ func parseFlags(flags *pflag.FlagSet) (err error) {
 	if err != nil {
 		return err
 	}
-	skewed, err = flags.GetBool(flagSkewed)
+	distribution, err = flags.GetString(flagDistribution)
+	if err != nil {
+		return err
+	}
+	zipfS, err = flags.GetFloat64(flagZipfS)
+	if err != nil {
+		return err
+	}
+	hotspotFraction, err = flags.GetFloat64(flagHotspotFraction)
 	if err != nil {
 		return err
 	}
@@ -74,11 +130,97 @@ func parseFlags(flags *pflag.FlagSet) (err error) {
 	if err != nil {
 		return err
 	}
+	configPath, err = flags.GetString(flagConfig)
+	if err != nil {
+		return err
+	}
+	prepareConcurrency, err = flags.GetInt(flagPrepareConcurrency)
+	if err != nil {
+		return err
+	}
+	connTLS.host, err = flags.GetString(flagHost)
+	if err != nil {
+		return err
+	}
+	connTLS.port, err = flags.GetInt(flagPort)
+	if err != nil {
+		return err
+	}
+	connTLS.user, err = flags.GetString(flagUser)
+	if err != nil {
+		return err
+	}
+	connTLS.password, err = flags.GetString(flagPassword)
+	if err != nil {
+		return err
+	}
+	connTLS.ca, err = flags.GetString(flagCA)
+	if err != nil {
+		return err
+	}
+	connTLS.cert, err = flags.GetString(flagCert)
+	if err != nil {
+		return err
+	}
+	connTLS.key, err = flags.GetString(flagKey)
+	if err != nil {
+		return err
+	}
+	if err := registerTLSConfig(connTLS); err != nil {
+		return err
+	}
+	loadMethod, err = flags.GetString(flagLoadMethod)
+	if err != nil {
+		return err
+	}
+	generatorParallelism, err = flags.GetInt(flagGeneratorParallelism)
+	if err != nil {
+		return err
+	}
+	outputDir, err = flags.GetString(flagOutput)
+	if err != nil {
+		return err
+	}
+	metricsOut, err = flags.GetString(flagMetricsOut)
+	if err != nil {
+		return err
+	}
+	pprofAddr, err = flags.GetString(flagPprofAddr)
+	if err != nil {
+		return err
+	}
+	dumplingPprofAddr, err = flags.GetString(flagDumplingPprofAddr)
+	if err != nil {
+		return err
+	}
+	source, err = flags.GetString(flagSource)
+	if err != nil {
+		return err
+	}
+	dumpTool, err = flags.GetString(flagDumpTool)
+	if err != nil {
+		return err
+	}
 	switch action {
 	case "all", "prepare", "run":
 	default:
 		return errors.Errorf("unknown action: %s", action)
 	}
+	switch loadMethod {
+	case loadMethodInsert, loadMethodInfile:
+	default:
+		return errors.Errorf("unknown load method: %s", loadMethod)
+	}
+	switch distribution {
+	case "uniform", "zipfian", "hotspot", "sequential-gap":
+	default:
+		return errors.Errorf("unknown distribution: %s", distribution)
+	}
+	switch source {
+	case sourceTiDB, sourceMySQL, sourcePostgres:
+	default:
+		return errors.Errorf("unknown source: %s", source)
+	}
 	return nil
 }
 
@@ -96,114 +238,126 @@ func main() {
 		fmt.Printf("\nmeet some unparsed arguments, please check again: %+v\n", pflag.Args())
 		os.Exit(1)
 	}
+	if pprofAddr != "" {
+		startPprofServer(pprofAddr)
+	}
 
 	ctx := context.Background()
-	pool, err := sql.Open("mysql", getDSN("root", "", "127.0.0.1", 4000))
-	if err != nil {
-		log.Fatalf("%v\n", err)
+	driverName, dsn := "mysql", getDSNWithTLS(connTLS)
+	if source == sourcePostgres {
+		driverName, dsn = "postgres", getPostgresDSN(connTLS)
 	}
-	conn, err := pool.Conn(ctx)
+	pool, err := sql.Open(driverName, dsn)
 	if err != nil {
 		log.Fatalf("%v\n", err)
 	}
-	err = runSQL(ctx, conn, "use test")
+
+	if configPath != "" {
+		if source == sourcePostgres {
+			log.Fatalf("--config is not yet supported with --source=postgres\n")
+		}
+		if source == sourceMySQL {
+			log.Fatalf("--config is not yet supported with --source=mysql: it issues TiDB-only region-split SQL\n")
+		}
+		cfg, err := loadBenchConfig(configPath)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if action == "all" || action == "prepare" {
+			if err := prepareWorkloads(ctx, pool, cfg, prepareConcurrency); err != nil {
+				log.Fatalf("%v\n", err)
+			}
+		}
+		if action == "all" || action == "run" {
+			results, err := runWorkloads(ctx, cfg)
+			if err != nil {
+				log.Fatalf("%v\n", err)
+			}
+			printWorkloadSummary(results)
+		}
+		return
+	}
+
+	src, err := newSourceDB(source, pool)
 	if err != nil {
 		log.Fatalf("%v\n", err)
 	}
 	if action == "all" || action == "prepare" {
-		err = prepareData(ctx, conn)
-		if err != nil {
+		if err := prepareData(ctx, src); err != nil {
 			log.Fatalf("%v\n", err)
 		}
 	}
 	if action == "all" || action == "run" {
-		err = dumpData(ctx)
-		if err != nil {
+		if err := dumpData(ctx, src); err != nil {
 			log.Fatalf("%v\n", err)
 		}
 	}
 }
 
-func getDSN(user, pass, host string, port int) string {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&readTimeout=%s&writeTimeout=30s&interpolateParams=true&maxAllowedPacket=0",
-		user, pass, host, port, "test", "900s")
-	return dsn
+// tableColumns are the fixed columns of the single table t prepares, shared
+// by the INSERT and LOAD DATA loading paths.
+var tableColumns = []ColumnSpec{
+	{Name: "a", Type: "bigint", PrimaryKey: true},
+	{Name: "b", Type: "int"},
+	{Name: "c", Type: "int"},
+	{Name: "d", Type: "varchar(255)"},
 }
 
-func prepareData(ctx context.Context, conn *sql.Conn) error {
-	dropSQL := "drop table if exists t;"
-	err := runSQL(ctx, conn, dropSQL)
-	if err != nil {
-		return err
+// singleTableSpec is the PrepareSpec for the single-table (non --config)
+// path, built from the top-level --rows/--regions/--distribution flags.
+func singleTableSpec() PrepareSpec {
+	return PrepareSpec{
+		Table:   "t",
+		Columns: tableColumns,
+		Rows:    rowCount,
+		Regions: regionCount,
+		Skew:    SkewSpec{Profile: distribution, ZipfS: zipfS, HotspotFraction: hotspotFraction},
 	}
-	createSQL := "create table t (a bigint primary key auto_increment, b int, c int, d varchar(255));"
-	err = runSQL(ctx, conn, createSQL)
-	if err != nil {
-		return err
-	}
-	if regionCount != 0 {
-		query := fmt.Sprintf("split table t between (0) and (%d) regions %d", rowCount, regionCount)
-		err := runSQL(ctx, conn, query)
-		if err != nil {
-			return err
-		}
+}
+
+func prepareData(ctx context.Context, src SourceDB) error {
+	return src.Prepare(ctx, singleTableSpec())
+}
+
+func dumpData(ctx context.Context, src SourceDB) error {
+	bin := dumplingBin
+	args := src.DumpArgs()
+	if source == sourcePostgres {
+		bin = dumpTool
+		args = append(args, "--table", "t", "--file", filepath.Join(outputDir, "dump.sql"))
+	} else {
+		args = append(args,
+			"--filter", "test.t",
+			"--output", outputDir,
+			"--logfile", "dump.log",
+			"--rows", fmt.Sprintf("%d", chunkRowCount),
+			"--loglevel", "debug",
+			"--threads", "32",
+		)
 	}
-	var sb strings.Builder
-	for i := 0; i < rowCount; i++ {
-		if sb.Len() > 1000000 {
-			err := runSQL(ctx, conn, fmt.Sprintf("insert into t values %s;", sb.String()))
-			if err != nil {
-				return err
-			}
-			sb.Reset()
-		}
-		if sb.Len() > 0 {
-			sb.WriteString(",")
-		}
-		sb.WriteString(fmt.Sprintf("(%d, %d, %d, '%s')", i+1, i+1, i+1, "string_payload_payload_payload"))
+	return dumpDataWithMetrics(ctx, bin, args, outputDir, metricsOut, dumplingPprofAddr, dumpRunInfo{
+		Rows:         rowCount,
+		ChunkRows:    chunkRowCount,
+		Regions:      regionCount,
+		Distribution: distribution,
+	})
+}
+
+// tlsDumplingArgs renders the TLS flags dumpling itself accepts, so the
+// benchmarked run authenticates against the server the same way the
+// prepare-phase connection does.
+func tlsDumplingArgs(s tlsSettings) []string {
+	var args []string
+	if s.ca != "" {
+		args = append(args, "--ca", s.ca)
 	}
-	if sb.Len() > 0 {
-		err := runSQL(ctx, conn, fmt.Sprintf("insert into t values %s;", sb.String()))
-		if err != nil {
-			return err
-		}
+	if s.cert != "" {
+		args = append(args, "--cert", s.cert)
 	}
-	if skewed {
-		query := fmt.Sprintf("insert into t values (%d, %d, %d, '%s');", 9223372035854775807, rowCount+1, rowCount+1, "string_payload_payload_payload")
-		err := runSQL(ctx, conn, query)
-		if err != nil {
-			return err
-		}
+	if s.key != "" {
+		args = append(args, "--key", s.key)
 	}
-	return nil
-}
-
-func dumpData(ctx context.Context) error {
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	start := time.Now()
-	args := []string{
-		"--host", "127.0.0.1",
-		"--port", "4000",
-		"--filter", "test.t",
-		"--tidb-mem-quota-query", "8589934592", /* 8 << 20 */
-		"--logfile", "dump.log",
-		"--rows", fmt.Sprintf("%d", chunkRowCount),
-		"--loglevel", "debug",
-		"--threads", "32",
-	}
-	cmd := exec.Command(dumplingBin, args...)
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	if err != nil {
-		log.Println(stderr.String())
-		return err
-	}
-	log.Println(stdout.String())
-	elapsed := time.Since(start)
-	log.Printf("dumpling took %s", elapsed)
-	return nil
+	return args
 }
 
 func runSQL(ctx context.Context, conn *sql.Conn, query string) error {
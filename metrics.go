@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// runMetrics is one machine-readable record of a single dumpData invocation,
+// written to --metrics-out for regression tracking across runs.
+type runMetrics struct {
+	Args          []string `json:"args"`
+	Rows          int      `json:"rows"`
+	ChunkRows     int      `json:"chunk_rows"`
+	Regions       int      `json:"regions"`
+	Distribution  string   `json:"distribution"`
+	WallTimeMS    int64    `json:"wall_time_ms"`
+	ExitStatus    string   `json:"exit_status"`
+	StdoutBytes   int      `json:"stdout_bytes"`
+	StderrBytes   int      `json:"stderr_bytes"`
+	OutputDirSize int64    `json:"output_dir_size_bytes"`
+}
+
+// writeMetricsRecord appends rec to path, formatting as CSV when path ends
+// in .csv and as a JSON line otherwise. The CSV header is written once, the
+// first time the file is created.
+func writeMetricsRecord(path string, rec runMetrics) error {
+	if strings.HasSuffix(path, ".csv") {
+		return appendMetricsCSV(path, rec)
+	}
+	return appendMetricsJSON(path, rec)
+}
+
+func appendMetricsJSON(path string, rec runMetrics) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "open metrics file %s", path)
+	}
+	defer f.Close()
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func appendMetricsCSV(path string, rec runMetrics) error {
+	_, err := os.Stat(path)
+	isNew := os.IsNotExist(err)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "open metrics file %s", path)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	if isNew {
+		if err := w.Write([]string{"args", "rows", "chunk_rows", "regions", "distribution", "wall_time_ms", "exit_status", "stdout_bytes", "stderr_bytes", "output_dir_size_bytes"}); err != nil {
+			return err
+		}
+	}
+	return w.Write([]string{
+		strings.Join(rec.Args, " "),
+		strconv.Itoa(rec.Rows),
+		strconv.Itoa(rec.ChunkRows),
+		strconv.Itoa(rec.Regions),
+		rec.Distribution,
+		strconv.FormatInt(rec.WallTimeMS, 10),
+		rec.ExitStatus,
+		strconv.Itoa(rec.StdoutBytes),
+		strconv.Itoa(rec.StderrBytes),
+		strconv.FormatInt(rec.OutputDirSize, 10),
+	})
+}
+
+// startPprofServer starts the harness's own net/http/pprof endpoint in the
+// background, so the dumpling_bench process itself can be profiled during a
+// run.
+func startPprofServer(addr string) {
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Printf("pprof server on %s stopped: %v", addr, err)
+		}
+	}()
+}
+
+const pprofMidCaptureDelay = 30 * time.Second
+
+// capturePprofSnapshots fetches heap, profile and goroutine profiles from the
+// dumpling process's pprof endpoint at dumplingPprofAddr, at the start and
+// end of the run, plus a best-effort mid-run sample, saving each into
+// snapshotDir.
+func capturePprofSnapshots(ctx context.Context, dumplingPprofAddr, snapshotDir string, done <-chan struct{}) {
+	fetch := func(label string) {
+		for _, profile := range []string{"heap", "profile", "goroutine"} {
+			if err := fetchPprofProfile(ctx, dumplingPprofAddr, profile, snapshotDir, label); err != nil {
+				log.Printf("capture %s pprof profile %s: %v", label, profile, err)
+			}
+		}
+	}
+	fetch("start")
+	select {
+	case <-done:
+		fetch("end")
+		return
+	case <-time.After(pprofMidCaptureDelay):
+		fetch("mid")
+	}
+	<-done
+	fetch("end")
+}
+
+func fetchPprofProfile(ctx context.Context, addr, profile, snapshotDir, label string) error {
+	url := fmt.Sprintf("http://%s/debug/pprof/%s", addr, profile)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	out := filepath.Join(snapshotDir, fmt.Sprintf("%s-%s.pprof", label, profile))
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
+
+// dumpRunInfo carries the rows/regions/distribution metadata for a single
+// dumpDataWithMetrics call, since that differs between dumpData's one
+// single-table run (the top-level --rows/--regions/--distribution flags) and
+// runWorkloads' one call per workload (that workload's own spec).
+type dumpRunInfo struct {
+	Rows         int
+	ChunkRows    int
+	Regions      int
+	Distribution string
+}
+
+// dumpDataWithMetrics runs bin with args, logs its output and elapsed time,
+// and additionally records a metrics record to metricsOut (when set) and, when
+// dumplingPprofAddr is set, capturing heap/profile/goroutine snapshots from
+// the dumpling process around the run.
+func dumpDataWithMetrics(ctx context.Context, bin string, args []string, outputDir, metricsOut, dumplingPprofAddr string, info dumpRunInfo) error {
+	var stdout, stderr bytes.Buffer
+	start := time.Now()
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	var done chan struct{}
+	if dumplingPprofAddr != "" {
+		snapshotDir := outputDir
+		if metricsOut != "" {
+			snapshotDir = filepath.Dir(metricsOut)
+		}
+		done = make(chan struct{})
+		go capturePprofSnapshots(ctx, dumplingPprofAddr, snapshotDir, done)
+	}
+
+	runErr := cmd.Run()
+	if done != nil {
+		close(done)
+	}
+	elapsed := time.Since(start)
+
+	if runErr != nil {
+		log.Println(stderr.String())
+	} else {
+		log.Println(stdout.String())
+	}
+	log.Printf("dumpling took %s", elapsed)
+
+	exitStatus := "ok"
+	if runErr != nil {
+		exitStatus = runErr.Error()
+	}
+
+	if metricsOut != "" {
+		_, size, err := dirStats(outputDir)
+		if err != nil {
+			return err
+		}
+		rec := runMetrics{
+			Args:          args,
+			Rows:          info.Rows,
+			ChunkRows:     info.ChunkRows,
+			Regions:       info.Regions,
+			Distribution:  info.Distribution,
+			WallTimeMS:    elapsed.Milliseconds(),
+			ExitStatus:    exitStatus,
+			StdoutBytes:   stdout.Len(),
+			StderrBytes:   stderr.Len(),
+			OutputDirSize: size,
+		}
+		if err := writeMetricsRecord(metricsOut, rec); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
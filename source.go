@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	sourceTiDB     = "tidb"
+	sourceMySQL    = "mysql"
+	sourcePostgres = "postgres"
+)
+
+// PrepareSpec is the schema/row-count/skew description passed to a SourceDB's
+// Prepare method; it mirrors the single-table flags (rowCount, regionCount,
+// distribution/zipfS/hotspotFraction) so both the single-table path and the
+// --config path can build one from their respective inputs.
+type PrepareSpec struct {
+	Table   string
+	Columns []ColumnSpec
+	Rows    int
+	Regions int
+	Skew    SkewSpec
+}
+
+// SourceDB abstracts over the database dumpling_bench prepares data in and
+// benchmarks a dump tool against, so the same harness can drive TiDB, plain
+// MySQL, or PostgreSQL with their own prepare strategies and dump tooling.
+type SourceDB interface {
+	Prepare(ctx context.Context, spec PrepareSpec) error
+	DumpArgs() []string
+}
+
+func newSourceDB(source string, pool *sql.DB) (SourceDB, error) {
+	switch source {
+	case "", sourceTiDB:
+		return &tidbSource{pool: pool}, nil
+	case sourceMySQL:
+		return &mysqlSource{pool: pool}, nil
+	case sourcePostgres:
+		return &postgresSource{pool: pool}, nil
+	default:
+		return nil, errors.Errorf("unknown source: %s", source)
+	}
+}
+
+// tidbSource is the original dumpling_bench behavior: it splits the table
+// into regions before loading rows, and benchmarks the dumpling binary.
+type tidbSource struct {
+	pool *sql.DB
+}
+
+func (s *tidbSource) Prepare(ctx context.Context, spec PrepareSpec) error {
+	conn, err := s.pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := runSQL(ctx, conn, "use test"); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, conn, fmt.Sprintf("drop table if exists %s;", spec.Table)); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, conn, createTableSQL(spec)); err != nil {
+		return err
+	}
+	if spec.Regions != 0 {
+		query := fmt.Sprintf("split table %s between (0) and (%d) regions %d", spec.Table, spec.Rows, spec.Regions)
+		if err := runSQL(ctx, conn, query); err != nil {
+			return err
+		}
+	}
+	if err := loadSpecRows(ctx, s.pool, conn, spec); err != nil {
+		return err
+	}
+	return reportRegionCounts(ctx, conn, spec.Table)
+}
+
+func (s *tidbSource) DumpArgs() []string {
+	args := []string{
+		"--host", connTLS.host,
+		"--port", fmt.Sprintf("%d", connTLS.port),
+		"--user", connTLS.user,
+		"--password", connTLS.password,
+		"--tidb-mem-quota-query", "8589934592", /* 8 << 30 */
+	}
+	return append(args, tlsDumplingArgs(connTLS)...)
+}
+
+// mysqlSource behaves like tidbSource but skips the TiDB-only region split
+// and omits TiDB-only dumpling flags.
+type mysqlSource struct {
+	pool *sql.DB
+}
+
+func (s *mysqlSource) Prepare(ctx context.Context, spec PrepareSpec) error {
+	conn, err := s.pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := runSQL(ctx, conn, "use test"); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, conn, fmt.Sprintf("drop table if exists %s;", spec.Table)); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, conn, createTableSQL(spec)); err != nil {
+		return err
+	}
+	return loadSpecRows(ctx, s.pool, conn, spec)
+}
+
+func (s *mysqlSource) DumpArgs() []string {
+	args := []string{
+		"--host", connTLS.host,
+		"--port", fmt.Sprintf("%d", connTLS.port),
+		"--user", connTLS.user,
+		"--password", connTLS.password,
+	}
+	return append(args, tlsDumplingArgs(connTLS)...)
+}
+
+// postgresSource prepares data in PostgreSQL via COPY FROM STDIN and
+// benchmarks --dump-tool (pg_dump or an equivalent) instead of dumpling.
+type postgresSource struct {
+	pool *sql.DB
+}
+
+func (s *postgresSource) Prepare(ctx context.Context, spec PrepareSpec) error {
+	if _, err := s.pool.ExecContext(ctx, fmt.Sprintf("drop table if exists %s;", spec.Table)); err != nil {
+		return err
+	}
+	if _, err := s.pool.ExecContext(ctx, createTableSQL(spec)); err != nil {
+		return err
+	}
+	return copyRows(ctx, s.pool, spec)
+}
+
+func (s *postgresSource) DumpArgs() []string {
+	return []string{
+		"--host", connTLS.host,
+		"--port", fmt.Sprintf("%d", connTLS.port),
+		"--username", connTLS.user,
+		"--no-password",
+		"--dbname", "test",
+	}
+}
+
+// copyRows bulk-loads spec.Rows rows into spec.Table using PostgreSQL's
+// COPY FROM STDIN via lib/pq's pq.CopyIn, the pg equivalent of dumpling
+// bench's LOAD DATA LOCAL INFILE path for MySQL/TiDB.
+func copyRows(ctx context.Context, pool *sql.DB, spec PrepareSpec) error {
+	colNames := make([]string, 0, len(spec.Columns))
+	for _, c := range spec.Columns {
+		colNames = append(colNames, c.Name)
+	}
+	dist, err := newDistribution(spec.Skew, spec.Rows, 0)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(spec.Table, colNames...))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for i := 0; i < spec.Rows; i++ {
+		if _, err := stmt.ExecContext(ctx, copyRowValues(spec.Columns, dist.next(i), i)...); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+			return errors.Wrapf(err, "copy row %d into %s", i, spec.Table)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		_ = tx.Rollback()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// copyRowValues renders one row as driver args for pq.CopyIn, mirroring
+// rowValues' column ordering (primary key gets key, varchar/text columns get
+// the filler payload, everything else gets i+1).
+func copyRowValues(columns []ColumnSpec, key int64, i int) []interface{} {
+	vals := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		switch {
+		case c.PrimaryKey:
+			vals = append(vals, key)
+		case strings.Contains(strings.ToLower(c.Type), "varchar") || strings.Contains(strings.ToLower(c.Type), "text"):
+			vals = append(vals, "string_payload_payload_payload")
+		default:
+			vals = append(vals, i+1)
+		}
+	}
+	return vals
+}
+
+func createTableSQL(spec PrepareSpec) string {
+	var cols []string
+	var pk []string
+	for _, c := range spec.Columns {
+		cols = append(cols, fmt.Sprintf("%s %s", c.Name, c.Type))
+		if c.PrimaryKey {
+			pk = append(pk, c.Name)
+		}
+	}
+	if len(pk) > 0 {
+		cols = append(cols, fmt.Sprintf("primary key (%s)", strings.Join(pk, ", ")))
+	}
+	return fmt.Sprintf("create table %s (%s);", spec.Table, strings.Join(cols, ", "))
+}
+
+func loadSpecRows(ctx context.Context, pool *sql.DB, conn *sql.Conn, spec PrepareSpec) error {
+	if loadMethod == loadMethodInfile {
+		return loadRowsBulk(ctx, pool, spec.Table, spec.Columns, spec.Rows, spec.Skew, generatorParallelism)
+	}
+	dist, err := newDistribution(spec.Skew, spec.Rows, 0)
+	if err != nil {
+		return err
+	}
+	return loadRows(ctx, conn, spec.Table, spec.Columns, spec.Rows, dist)
+}